@@ -8,6 +8,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 )
 
@@ -15,10 +16,66 @@ type Config struct {
 	Locations map[string]SiteConfig `json:"locations"`
 	CFTurn    CFTurnConfig          `json:"cfTurnstile"`
 	SMTP      SMTPConfig            `json:"smtp"`
+	Mail      MailConfig            `json:"mail"`
+	Forms     map[string]FormConfig `json:"forms"`
 	Log       LogConfig             `json:"log"`
 	Options   OptionsConfig         `json:"options"`
 }
 
+// FormConfig routes one CTA form ID ("contact", "quote", "careers", ...) to
+// its own recipients. When Config.Forms is non-empty, ctaHandler requires
+// every submission to name a form present in this map. SubjectTemplate, when
+// set, overrides MailConfig.SubjectTemplate for this form only.
+type FormConfig struct {
+	To              []string `json:"to"`
+	CC              []string `json:"cc"`
+	BCC             []string `json:"bcc"`
+	SubjectTemplate string   `json:"subjectTemplate"`
+}
+
+// MailConfig selects which delivery backend ctaHandler's mail.Transport is
+// built from. Backend defaults to "smtp", which uses the top-level SMTP
+// block; the HTTP-API backends below are opt-in alternatives for
+// environments where outbound SMTP is blocked.
+type MailConfig struct {
+	Backend  string         `json:"backend"` // "smtp", "mailgun", "sendgrid" or "ses"
+	Mailgun  MailgunConfig  `json:"mailgun"`
+	SendGrid SendGridConfig `json:"sendgrid"`
+	SES      SESConfig      `json:"ses"`
+
+	// SubjectTemplate and HTMLTemplate are html/template (+ sprig) files
+	// rendered against a mail.TemplateData; both fall back to the embedded
+	// sanitized-table defaults when left empty. TextTemplate has no
+	// default: set it to also send a text/plain multipart/alternative part.
+	SubjectTemplate string `json:"subjectTemplate"`
+	HTMLTemplate    string `json:"htmlTemplate"`
+	TextTemplate    string `json:"textTemplate"`
+}
+
+type MailgunConfig struct {
+	APIKey  string `json:"apiKey"`
+	Domain  string `json:"domain"`
+	BaseURL string `json:"baseUrl"` // defaults to https://api.mailgun.net/v3, override for the EU region
+	From    string `json:"fromAddress"`
+	To      string `json:"toAddress"`
+}
+
+type SendGridConfig struct {
+	APIKey  string `json:"apiKey"`
+	BaseURL string `json:"baseUrl"` // defaults to https://api.sendgrid.com/v3
+	From    string `json:"fromAddress"`
+	To      string `json:"toAddress"`
+}
+
+type SESConfig struct {
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	Region          string `json:"region"`
+	BaseURL         string `json:"baseUrl"` // defaults to https://email.<region>.amazonaws.com; override to point at a test double
+	From            string `json:"fromAddress"`
+	To              string `json:"toAddress"`
+}
+
 type SiteConfig struct {
 	Dir      string   `json:"dir"`
 	Type     string   `json:"type"`     // "static" or "spa"
@@ -40,6 +97,30 @@ type SMTPConfig struct {
 	VerifyTLS  bool   `json:"verifyTls"`
 	From       string `json:"fromAddress"`
 	To         string `json:"toAddress"`
+
+	// AuthMechanism selects the SASL mechanism used to authenticate with the
+	// server: "plain" (default), "login", "cram-md5", "scram-sha-1",
+	// "scram-sha-256", "xoauth2" or "oauthbearer".
+	AuthMechanism string `json:"authMechanism"`
+
+	// TokenSource supplies the bearer token for "xoauth2"/"oauthbearer". It
+	// is ignored for the other mechanisms, which use Username/Password.
+	TokenSource TokenSourceConfig `json:"tokenSource"`
+}
+
+// TokenSourceConfig describes where to obtain an OAuth2 bearer token for the
+// "xoauth2"/"oauthbearer" SASL mechanisms. Exactly one of Static, Command or
+// URL should be set; they are tried in that order.
+type TokenSourceConfig struct {
+	// Static is a pre-issued token, used as-is. Mostly useful for testing or
+	// for tokens refreshed by an external process that rewrites the config.
+	Static string `json:"static"`
+	// Command is executed via the shell and its trimmed stdout is used as
+	// the token, e.g. a wrapper around `gcloud auth print-access-token`.
+	Command string `json:"command"`
+	// URL is fetched with an HTTP GET and the response body is used as the
+	// token (e.g. an internal token-vending endpoint).
+	URL string `json:"url"`
 }
 
 type LogConfig struct {
@@ -48,10 +129,45 @@ type LogConfig struct {
 }
 
 type OptionsConfig struct {
-	EnableRateLimiting bool   `json:"enableRateLimiting"`
-	MaxBodySize        int64  `json:"maxBodySize"`
-	BlockBotUserAgents bool   `json:"blockBotUserAgents"`
-	CTAEndpoint        string `json:"ctaEndpoint"`
+	EnableRateLimiting bool              `json:"enableRateLimiting"`
+	RateLimitBuckets   []RateLimitBucket `json:"rateLimitBuckets"`
+	MaxBodySize        int64             `json:"maxBodySize"`
+	BlockBotUserAgents bool              `json:"blockBotUserAgents"`
+	CTAEndpoint        string            `json:"ctaEndpoint"`
+
+	// RateLimitStore selects the ratelimit.RateStore backend: "memory"
+	// (default) keeps bucket state in-process, "redis" shares it across
+	// replicas through RateLimitRedisAddr and requires the binary to have
+	// been built with -tags redis.
+	RateLimitStore string `json:"rateLimitStore"`
+	// RateLimitRedisAddr is the "host:port" of the Redis instance backing
+	// RateLimitStore "redis".
+	RateLimitRedisAddr string `json:"rateLimitRedisAddr"`
+
+	// ResponseSigningKey is the path to a PEM-encoded PKCS8 private key
+	// (RSA or Ed25519) used to sign the query string of return_url
+	// redirects, so the frontend can tell a genuine response from a
+	// crafted one. Leave empty to disable signed redirects.
+	ResponseSigningKey string `json:"responseSigningKey"`
+
+	// AllowedReturnHosts lists the hostnames a caller-supplied return_url is
+	// permitted to point at. A host-relative return_url (no scheme/host,
+	// e.g. "/thanks") is always allowed; anything with a host must match an
+	// entry here, or ctaHandler refuses to redirect to it.
+	AllowedReturnHosts []string `json:"allowedReturnHosts"`
+}
+
+// RateLimitBucket configures one leaky bucket applied to requests whose path
+// starts with PathPrefix. When several buckets match a path, the one with
+// the longest PathPrefix wins.
+type RateLimitBucket struct {
+	PathPrefix string  `json:"pathPrefix"`
+	Capacity   float64 `json:"capacity"` // burst size, in requests
+	LeakRate   float64 `json:"leakRate"` // requests/sec the bucket drains at
+	// KeyBy selects what requests are bucketed by: "ip" (default),
+	// "ip+ua", or "header:X-Api-Key" to key by the client IP together with
+	// that request header's value.
+	KeyBy string `json:"keyBy"`
 }
 
 func LoadConfig(path string) (Config, error) {
@@ -67,5 +183,15 @@ func LoadConfig(path string) (Config, error) {
 	if c.Options.MaxBodySize == 0 {
 		c.Options.MaxBodySize = 4096
 	}
+
+	for i, b := range c.Options.RateLimitBuckets {
+		if b.Capacity <= 0 {
+			return c, fmt.Errorf("config: rateLimitBuckets[%d] (%s): capacity must be > 0", i, b.PathPrefix)
+		}
+		if b.LeakRate <= 0 {
+			return c, fmt.Errorf("config: rateLimitBuckets[%d] (%s): leakRate must be > 0", i, b.PathPrefix)
+		}
+	}
+
 	return c, nil
 }
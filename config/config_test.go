@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("writing test config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigRejectsZeroLeakRate(t *testing.T) {
+	path := writeConfig(t, `{"options":{"rateLimitBuckets":[{"pathPrefix":"/-/cta","capacity":5}]}}`)
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("LoadConfig accepted a bucket with no leakRate (defaults to 0)")
+	}
+}
+
+func TestLoadConfigRejectsZeroCapacity(t *testing.T) {
+	path := writeConfig(t, `{"options":{"rateLimitBuckets":[{"pathPrefix":"/-/cta","leakRate":1}]}}`)
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("LoadConfig accepted a bucket with no capacity (defaults to 0)")
+	}
+}
+
+func TestLoadConfigAcceptsValidBucket(t *testing.T) {
+	path := writeConfig(t, `{"options":{"rateLimitBuckets":[{"pathPrefix":"/-/cta","capacity":5,"leakRate":1}]}}`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Options.RateLimitBuckets) != 1 {
+		t.Fatalf("got %d buckets, want 1", len(cfg.Options.RateLimitBuckets))
+	}
+}
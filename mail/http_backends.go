@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+// httpClient is shared by the HTTP-API backends; none of them need
+// connection pooling tuned beyond the defaults.
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// mailgunTransport delivers messages via the Mailgun HTTP API:
+// https://documentation.mailgun.com/en/latest/api-sending.html
+type mailgunTransport struct {
+	cfg config.MailgunConfig
+}
+
+// NewMailgunTransport builds a Transport backed by the Mailgun HTTP API.
+func NewMailgunTransport(cfg config.MailgunConfig) (Transport, error) {
+	if cfg.APIKey == "" || cfg.Domain == "" {
+		return nil, fmt.Errorf("mail: mailgun apiKey and domain are required")
+	}
+	return &mailgunTransport{cfg: cfg}, nil
+}
+
+func (t *mailgunTransport) Send(msg *Message) error {
+	base := t.cfg.BaseURL
+	if base == "" {
+		base = "https://api.mailgun.net/v3"
+	}
+	endpoint := strings.TrimRight(base, "/") + "/" + t.cfg.Domain + "/messages"
+
+	form := url.Values{}
+	form.Set("from", msg.From)
+	for _, to := range msg.To {
+		form.Add("to", to)
+	}
+	for _, cc := range msg.Cc {
+		form.Add("cc", cc)
+	}
+	for _, bcc := range msg.Bcc {
+		form.Add("bcc", bcc)
+	}
+	form.Set("subject", msg.Subject)
+	form.Set("html", msg.HTML)
+	if msg.Text != "" {
+		form.Set("text", msg.Text)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", t.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, body, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		warnBackendRefuse("mailgun", resp.StatusCode, body)
+		return fmt.Errorf("mailgun: status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// sendgridTransport delivers messages via the SendGrid v3 Mail Send API:
+// https://docs.sendgrid.com/api-reference/mail-send/mail-send
+type sendgridTransport struct {
+	cfg config.SendGridConfig
+}
+
+// NewSendGridTransport builds a Transport backed by the SendGrid HTTP API.
+func NewSendGridTransport(cfg config.SendGridConfig) (Transport, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("mail: sendgrid apiKey is required")
+	}
+	return &sendgridTransport{cfg: cfg}, nil
+}
+
+func (t *sendgridTransport) Send(msg *Message) error {
+	base := t.cfg.BaseURL
+	if base == "" {
+		base = "https://api.sendgrid.com/v3"
+	}
+	endpoint := strings.TrimRight(base, "/") + "/mail/send"
+
+	type addr struct {
+		Email string `json:"email"`
+	}
+	payload := struct {
+		Personalizations []struct {
+			To  []addr `json:"to"`
+			Cc  []addr `json:"cc,omitempty"`
+			Bcc []addr `json:"bcc,omitempty"`
+		} `json:"personalizations"`
+		From    addr   `json:"from"`
+		Subject string `json:"subject"`
+		Content []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"content"`
+	}{
+		From:    addr{Email: msg.From},
+		Subject: msg.Subject,
+	}
+	var to, cc, bcc []addr
+	for _, t := range msg.To {
+		to = append(to, addr{Email: t})
+	}
+	for _, t := range msg.Cc {
+		cc = append(cc, addr{Email: t})
+	}
+	for _, t := range msg.Bcc {
+		bcc = append(bcc, addr{Email: t})
+	}
+	payload.Personalizations = []struct {
+		To  []addr `json:"to"`
+		Cc  []addr `json:"cc,omitempty"`
+		Bcc []addr `json:"bcc,omitempty"`
+	}{{To: to, Cc: cc, Bcc: bcc}}
+	if msg.Text != "" {
+		payload.Content = append(payload.Content, struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		}{Type: "text/plain", Value: msg.Text})
+	}
+	payload.Content = append(payload.Content, struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}{Type: "text/html", Value: msg.HTML})
+
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, body, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		warnBackendRefuse("sendgrid", resp.StatusCode, body)
+		return fmt.Errorf("sendgrid: status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// doRequest executes req and returns the response together with its fully
+// drained body, so callers can log provider error bodies on failure.
+func doRequest(req *http.Request) (*http.Response, string, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, "", err
+	}
+	return resp, string(body), nil
+}
+
+// warnBackendRefuse logs a provider rejection in the same structured,
+// field-based style as main.warnRefuse.
+func warnBackendRefuse(backend string, status int, body string) {
+	log.WithFields(log.Fields{"backend": backend, "status": status, "body": body}).Warn("mail backend rejected message")
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+func TestMailgunTransportSendsExpectedRequest(t *testing.T) {
+	var gotMethod, gotPath, gotUser, gotPass, gotContentType string
+	var gotForm url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotUser, gotPass, _ = r.BasicAuth()
+		gotContentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		gotForm, _ = url.ParseQuery(string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport, err := NewMailgunTransport(config.MailgunConfig{
+		APIKey: "key-123", Domain: "mail.example.com", BaseURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewMailgunTransport: %v", err)
+	}
+
+	msg := &Message{
+		From: "noreply@example.com", To: []string{"a@example.com"}, Cc: []string{"b@example.com"},
+		Subject: "Hi", HTML: "<p>hi</p>", Text: "hi",
+	}
+	if err := transport.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if gotMethod != http.MethodPost {
+		t.Errorf("method = %q, want POST", gotMethod)
+	}
+	if gotPath != "/mail.example.com/messages" {
+		t.Errorf("path = %q, want /mail.example.com/messages", gotPath)
+	}
+	if gotUser != "api" || gotPass != "key-123" {
+		t.Errorf("basic auth = %q/%q, want api/key-123", gotUser, gotPass)
+	}
+	if gotContentType != "application/x-www-form-urlencoded" {
+		t.Errorf("content-type = %q, want application/x-www-form-urlencoded", gotContentType)
+	}
+	if got := gotForm.Get("from"); got != "noreply@example.com" {
+		t.Errorf("from = %q, want noreply@example.com", got)
+	}
+	if !reflect.DeepEqual(gotForm["to"], []string{"a@example.com"}) {
+		t.Errorf("to = %v, want [a@example.com]", gotForm["to"])
+	}
+	if !reflect.DeepEqual(gotForm["cc"], []string{"b@example.com"}) {
+		t.Errorf("cc = %v, want [b@example.com]", gotForm["cc"])
+	}
+	if got := gotForm.Get("text"); got != "hi" {
+		t.Errorf("text = %q, want hi", got)
+	}
+}
+
+func TestMailgunTransportRejectsNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad domain", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	transport, err := NewMailgunTransport(config.MailgunConfig{APIKey: "key", Domain: "mail.example.com", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewMailgunTransport: %v", err)
+	}
+	if err := transport.Send(&Message{From: "a@example.com", To: []string{"b@example.com"}}); err == nil {
+		t.Fatal("Send succeeded, want error on 403")
+	}
+}
+
+func TestSendGridTransportSendsExpectedRequest(t *testing.T) {
+	var gotMethod, gotPath, gotAuth, gotContentType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport, err := NewSendGridTransport(config.SendGridConfig{APIKey: "sg-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewSendGridTransport: %v", err)
+	}
+
+	msg := &Message{From: "noreply@example.com", To: []string{"a@example.com"}, Subject: "Hi", HTML: "<p>hi</p>"}
+	if err := transport.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if gotMethod != http.MethodPost {
+		t.Errorf("method = %q, want POST", gotMethod)
+	}
+	if gotPath != "/mail/send" {
+		t.Errorf("path = %q, want /mail/send", gotPath)
+	}
+	if gotAuth != "Bearer sg-key" {
+		t.Errorf("Authorization = %q, want Bearer sg-key", gotAuth)
+	}
+	if gotContentType != "application/json" {
+		t.Errorf("content-type = %q, want application/json", gotContentType)
+	}
+
+	var payload struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+		From struct {
+			Email string `json:"email"`
+		} `json:"from"`
+		Subject string `json:"subject"`
+		Content []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("unmarshal request body: %v", err)
+	}
+	if payload.From.Email != "noreply@example.com" {
+		t.Errorf("from.email = %q, want noreply@example.com", payload.From.Email)
+	}
+	if len(payload.Personalizations) != 1 || len(payload.Personalizations[0].To) != 1 ||
+		payload.Personalizations[0].To[0].Email != "a@example.com" {
+		t.Errorf("personalizations = %+v, want one entry to a@example.com", payload.Personalizations)
+	}
+	if payload.Subject != "Hi" {
+		t.Errorf("subject = %q, want Hi", payload.Subject)
+	}
+	if len(payload.Content) != 1 || payload.Content[0].Type != "text/html" {
+		t.Errorf("content = %+v, want one text/html entry", payload.Content)
+	}
+}
+
+func TestSendGridTransportRejectsNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad api key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	transport, err := NewSendGridTransport(config.SendGridConfig{APIKey: "bad-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewSendGridTransport: %v", err)
+	}
+	if err := transport.Send(&Message{From: "a@example.com", To: []string{"b@example.com"}}); err == nil {
+		t.Fatal("Send succeeded, want error on 401")
+	}
+}
@@ -7,11 +7,8 @@
 package mail
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"net"
-	"net/smtp"
 	"regexp"
 	"sort"
 	"strings"
@@ -23,124 +20,90 @@ var (
 	reHTML = regexp.MustCompile(`<[^>]*>`)
 	reURL  = regexp.MustCompile(`https?://\S+`)
 	reCtrl = regexp.MustCompile(`[\x00-\x09\x0B\x0C\x0E-\x1F]`)
+	reCRLF = regexp.MustCompile(`[\r\n]+`)
 )
 
-func SendMail(cfg config.SMTPConfig, subject string, payload map[string]interface{}) error {
-	addr := fmt.Sprintf("%s:%d", cfg.Server, cfg.Port)
-	body := buildEmailBody(payload)
-
-	msg := "From: " + cfg.From + "\r\n" +
-		"To: " + cfg.To + "\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"MIME-Version: 1.0\r\n" +
-		"Content-Type: text/html; charset=UTF-8\r\n" +
-		"\r\n" + body
-
-	switch strings.ToLower(cfg.Encryption) {
-	case "ssl":
-		// Verify SSL
-		tlsconf := &tls.Config{InsecureSkipVerify: !cfg.VerifyTLS, ServerName: cfg.Server}
-		conn, err := tls.Dial("tcp", addr, tlsconf)
-		if err != nil {
-			return err
-		}
-		c, err := smtp.NewClient(conn, cfg.Server)
-		if err != nil {
-			return err
-		}
-		defer c.Quit()
-		if cfg.Username != "" {
-			auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Server)
-			if err := c.Auth(auth); err != nil {
-				return err
-			}
-		}
-		if err := c.Mail(cfg.From); err != nil {
-			return err
-		}
-		if err := c.Rcpt(cfg.To); err != nil {
-			return err
-		}
-		w, err := c.Data()
-		if err != nil {
-			return err
-		}
-		_, err = w.Write([]byte(msg))
-		if err != nil {
-			return err
-		}
-		return w.Close()
-	case "starttls":
-		conn, err := net.Dial("tcp", addr)
-		if err != nil {
-			return err
-		}
-		c, err := smtp.NewClient(conn, cfg.Server)
-		if err != nil {
-			return err
-		}
-		defer c.Quit()
-		tlsconf := &tls.Config{InsecureSkipVerify: !cfg.VerifyTLS, ServerName: cfg.Server}
-		if ok, _ := c.Extension("STARTTLS"); ok {
-			if err := c.StartTLS(tlsconf); err != nil {
-				return err
-			}
-		}
-		if cfg.Username != "" {
-			auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Server)
-			if err := c.Auth(auth); err != nil {
-				return err
-			}
-		}
-		if err := c.Mail(cfg.From); err != nil {
-			return err
-		}
-		if err := c.Rcpt(cfg.To); err != nil {
-			return err
-		}
-		w, err := c.Data()
-		if err != nil {
-			return err
-		}
-		_, err = w.Write([]byte(msg))
-		if err != nil {
-			return err
-		}
-		return w.Close()
+// BuildMessage renders data through ts and wraps the result, together with
+// the from/to/cc/bcc resolved for formID and the configured backend, into a
+// Transport-ready Message. formID selects a cfg.Forms override, if any; pass
+// the empty string when the caller has no forms configured.
+func BuildMessage(ts *TemplateSet, cfg config.Config, formID string, data TemplateData) (*Message, error) {
+	data.Rows = buildRows(data.Payload)
+
+	subject, html, text, err := ts.Render(formID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, cc, bcc := resolveRecipients(cfg, formID)
+	return &Message{
+		From:    from,
+		To:      to,
+		Cc:      cc,
+		Bcc:     bcc,
+		Subject: sanitizeHeaderValue(subject),
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
+// sanitizeHeaderValue strips CR/LF from a rendered template value before it
+// is used as a raw message header (e.g. Subject): Subject can embed
+// arbitrary payload fields via SubjectTemplate, and a value like
+// "foo\r\nBcc: attacker@evil.com" would otherwise inject extra headers into
+// RawMessage's output.
+func sanitizeHeaderValue(s string) string {
+	return strings.TrimSpace(reCRLF.ReplaceAllString(s, " "))
+}
+
+// resolveRecipients picks the from/to/cc/bcc addresses for a message. The
+// "from" address and default "to" always come from the configured backend;
+// when formID names an entry in cfg.Forms with a non-empty To, its To/CC/BCC
+// override the backend default recipient. A form entry with an empty To
+// falls back to the default recipient, and its CC/BCC are not used.
+func resolveRecipients(cfg config.Config, formID string) (from string, to, cc, bcc []string) {
+	from, defaultTo := resolveFromTo(cfg)
+
+	if form, ok := cfg.Forms[formID]; ok && len(form.To) > 0 {
+		return from, form.To, form.CC, form.BCC
+	}
+	return from, []string{defaultTo}, nil, nil
+}
+
+// resolveFromTo picks the from/to addresses for whichever backend cfg.Mail
+// selects, falling back to the SMTP block for the default "smtp" backend.
+func resolveFromTo(cfg config.Config) (from, to string) {
+	switch strings.ToLower(cfg.Mail.Backend) {
+	case "mailgun":
+		return cfg.Mail.Mailgun.From, cfg.Mail.Mailgun.To
+	case "sendgrid":
+		return cfg.Mail.SendGrid.From, cfg.Mail.SendGrid.To
+	case "ses":
+		return cfg.Mail.SES.From, cfg.Mail.SES.To
 	default:
-		// plain
-		if cfg.Username != "" {
-			auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Server)
-			return smtp.SendMail(addr, auth, cfg.From, []string{cfg.To}, []byte(msg))
-		} else {
-			return smtp.SendMail(addr, nil, cfg.From, []string{cfg.To}, []byte(msg))
-		}
+		return cfg.SMTP.From, cfg.SMTP.To
 	}
 }
 
-func buildEmailBody(payload map[string]interface{}) string {
-	var b strings.Builder
-	b.WriteString(formatPayloadAsTable(payload))
-	return b.String()
+// TableRow is one sanitized field/value pair of a submitted payload, as
+// consumed by the default "table" templates.
+type TableRow struct {
+	Key   string
+	Value string
 }
 
-func formatPayloadAsTable(payload map[string]interface{}) string {
+// buildRows turns payload into sorted, sanitized rows: HTML tags, bare URLs
+// and control characters are stripped from the row values, so a template
+// that renders .Rows never sees markup from the payload. The unmodified
+// payload is still exposed to templates as .Payload.
+func buildRows(payload map[string]interface{}) []TableRow {
 	var keys []string
 	for k := range payload {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	var b strings.Builder
-	b.WriteString(`<html>
-	<h1>New Submission</h1>
-
-  <table width="600" style="border:1px solid #333">
-    <thead>
-		  <tr><th align="left">Field</th><th align="left">Value</th></tr>
-		</thead>
-   <tbody>\n`)
-
+	rows := make([]TableRow, 0, len(keys))
 	for _, k := range keys {
 		v := payload[k]
 
@@ -153,25 +116,12 @@ func formatPayloadAsTable(payload map[string]interface{}) string {
 			vstr = string(j)
 		}
 
-		// sanitize: remove HTML, URLs and control chars (reuse package regexes)
 		vstr = reHTML.ReplaceAllString(vstr, "")
 		vstr = reURL.ReplaceAllString(vstr, "")
 		vstr = reCtrl.ReplaceAllString(vstr, " ")
 		vstr = strings.TrimSpace(vstr)
 
-		// escape pipe characters in keys to keep table valid
-		escapedKey := strings.ReplaceAll(k, "<", "&lt;")
-		escapedKey = strings.ReplaceAll(escapedKey, ">", "&gt;")
-
-		// write row with fenced code block for the value
-		b.WriteString(`<tr>
-		  <td><code>` + escapedKey + `</code></td>
-			<td><pre>` + vstr + `</pre></td>
-		</tr>\n`)
+		rows = append(rows, TableRow{Key: k, Value: vstr})
 	}
-	b.WriteString(`</tbody>
-	  </table>
-	</html>\n`)
-
-	return b.String()
+	return rows
 }
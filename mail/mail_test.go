@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+func TestResolveRecipientsDefaultsWithoutForms(t *testing.T) {
+	cfg := config.Config{SMTP: config.SMTPConfig{From: "noreply@example.com", To: "team@example.com"}}
+
+	from, to, cc, bcc := resolveRecipients(cfg, "")
+	if from != "noreply@example.com" {
+		t.Errorf("from = %q, want noreply@example.com", from)
+	}
+	if !reflect.DeepEqual(to, []string{"team@example.com"}) {
+		t.Errorf("to = %v, want [team@example.com]", to)
+	}
+	if cc != nil || bcc != nil {
+		t.Errorf("cc = %v, bcc = %v, want nil, nil", cc, bcc)
+	}
+}
+
+func TestResolveRecipientsFormOverride(t *testing.T) {
+	cfg := config.Config{
+		SMTP: config.SMTPConfig{From: "noreply@example.com", To: "team@example.com"},
+		Forms: map[string]config.FormConfig{
+			"careers": {To: []string{"hr@example.com"}, CC: []string{"mgr@example.com"}, BCC: []string{"audit@example.com"}},
+		},
+	}
+
+	from, to, cc, bcc := resolveRecipients(cfg, "careers")
+	if from != "noreply@example.com" {
+		t.Errorf("from = %q, want noreply@example.com", from)
+	}
+	if !reflect.DeepEqual(to, []string{"hr@example.com"}) {
+		t.Errorf("to = %v, want [hr@example.com]", to)
+	}
+	if !reflect.DeepEqual(cc, []string{"mgr@example.com"}) {
+		t.Errorf("cc = %v, want [mgr@example.com]", cc)
+	}
+	if !reflect.DeepEqual(bcc, []string{"audit@example.com"}) {
+		t.Errorf("bcc = %v, want [audit@example.com]", bcc)
+	}
+}
+
+func TestResolveRecipientsUnknownFormFallsBackToDefault(t *testing.T) {
+	cfg := config.Config{
+		SMTP:  config.SMTPConfig{From: "noreply@example.com", To: "team@example.com"},
+		Forms: map[string]config.FormConfig{"careers": {To: []string{"hr@example.com"}}},
+	}
+
+	_, to, _, _ := resolveRecipients(cfg, "contact")
+	if !reflect.DeepEqual(to, []string{"team@example.com"}) {
+		t.Errorf("to = %v, want [team@example.com] (default, unknown form)", to)
+	}
+}
+
+func TestResolveRecipientsFormWithEmptyToFallsBackToDefault(t *testing.T) {
+	cfg := config.Config{
+		SMTP:  config.SMTPConfig{From: "noreply@example.com", To: "team@example.com"},
+		Forms: map[string]config.FormConfig{"careers": {CC: []string{"mgr@example.com"}}},
+	}
+
+	_, to, cc, _ := resolveRecipients(cfg, "careers")
+	if !reflect.DeepEqual(to, []string{"team@example.com"}) {
+		t.Errorf("to = %v, want [team@example.com] (form.To empty, falls back)", to)
+	}
+	if cc != nil {
+		t.Errorf("cc = %v, want nil when falling back to the default recipient", cc)
+	}
+}
+
+func TestResolveRecipientsPerBackendFromTo(t *testing.T) {
+	cfg := config.Config{
+		Mail: config.MailConfig{
+			Backend: "mailgun",
+			Mailgun: config.MailgunConfig{From: "noreply@mg.example.com", To: "team@mg.example.com"},
+		},
+		SMTP: config.SMTPConfig{From: "noreply@smtp.example.com", To: "team@smtp.example.com"},
+	}
+
+	from, to, _, _ := resolveRecipients(cfg, "")
+	if from != "noreply@mg.example.com" {
+		t.Errorf("from = %q, want the mailgun From, not the SMTP one", from)
+	}
+	if !reflect.DeepEqual(to, []string{"team@mg.example.com"}) {
+		t.Errorf("to = %v, want [team@mg.example.com]", to)
+	}
+}
+
+// testTemplateSet builds a minimal TemplateSet from literal strings, without
+// touching the filesystem or the embedded defaults.
+func testTemplateSet(t *testing.T, subjectSrc, htmlSrc string) *TemplateSet {
+	t.Helper()
+	subject, err := template.New("subject").Parse(subjectSrc)
+	if err != nil {
+		t.Fatalf("parse subject: %v", err)
+	}
+	html, err := template.New("html").Parse(htmlSrc)
+	if err != nil {
+		t.Fatalf("parse html: %v", err)
+	}
+	return &TemplateSet{subject: subject, html: html}
+}
+
+func TestBuildMessageStripsCRLFFromSubject(t *testing.T) {
+	ts := testTemplateSet(t, "New lead: {{ .Payload.name }}", "{{ .Payload.name }}")
+	cfg := config.Config{SMTP: config.SMTPConfig{From: "noreply@example.com", To: "team@example.com"}}
+
+	msg, err := BuildMessage(ts, cfg, "", TemplateData{
+		Payload: map[string]interface{}{"name": "evil\r\nBcc: attacker@evil.com"},
+	})
+	if err != nil {
+		t.Fatalf("BuildMessage: %v", err)
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		t.Fatalf("Subject contains raw CR/LF: %q", msg.Subject)
+	}
+}
+
+func TestBuildMessageRoutesToFormRecipients(t *testing.T) {
+	ts := testTemplateSet(t, "Subject", "Body")
+	cfg := config.Config{
+		SMTP:  config.SMTPConfig{From: "noreply@example.com", To: "team@example.com"},
+		Forms: map[string]config.FormConfig{"careers": {To: []string{"hr@example.com"}}},
+	}
+
+	msg, err := BuildMessage(ts, cfg, "careers", TemplateData{Payload: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("BuildMessage: %v", err)
+	}
+	if !reflect.DeepEqual(msg.To, []string{"hr@example.com"}) {
+		t.Errorf("To = %v, want [hr@example.com]", msg.To)
+	}
+}
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-sasl"
+)
+
+// go-sasl only ships Plain, Login, OAuthBearer, Anonymous and External
+// clients; CRAM-MD5, SCRAM-SHA-1/256 and XOAUTH2 have no client there, so
+// they are implemented by hand below against its sasl.Client interface.
+
+// xoauth2Client implements the (non-standard, Google-originated) XOAUTH2
+// mechanism: a single initial response, no further challenge/response round
+// trip beyond an empty reply to an error challenge.
+type xoauth2Client struct {
+	username, token string
+}
+
+func newXoauth2Client(username, token string) sasl.Client {
+	return &xoauth2Client{username: username, token: token}
+}
+
+func (c *xoauth2Client) Start() (mech string, ir []byte, err error) {
+	ir = []byte(fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", c.username, c.token))
+	return "XOAUTH2", ir, nil
+}
+
+func (c *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	// A non-empty challenge here is the server's JSON error response; it
+	// expects an empty reply so it can close out the failed exchange.
+	return []byte{}, nil
+}
+
+// cramMD5Client implements CRAM-MD5 (RFC 2195): the server sends a single
+// challenge and the client replies with "username hex(hmac-md5(password,
+// challenge))".
+type cramMD5Client struct {
+	username, password string
+}
+
+func newCramMD5Client(username, password string) sasl.Client {
+	return &cramMD5Client{username: username, password: password}
+}
+
+func (c *cramMD5Client) Start() (mech string, ir []byte, err error) {
+	return "CRAM-MD5", nil, nil
+}
+
+func (c *cramMD5Client) Next(challenge []byte) ([]byte, error) {
+	mac := hmac.New(md5.New, []byte(c.password))
+	mac.Write(challenge)
+	return []byte(c.username + " " + hex.EncodeToString(mac.Sum(nil))), nil
+}
+
+// scramClient implements the non-channel-binding SCRAM-SHA-1 and
+// SCRAM-SHA-256 mechanisms (RFC 5802); the "-PLUS" channel-binding variants
+// are not supported.
+type scramClient struct {
+	mech     string
+	newHash  func() hash.Hash
+	username string
+	password string
+
+	step            int
+	clientNonce     string
+	clientFirstBare string
+	saltedPassword  []byte
+	authMessage     string
+}
+
+func newScramClient(mech string, newHash func() hash.Hash, username, password string) sasl.Client {
+	return &scramClient{mech: mech, newHash: newHash, username: username, password: password}
+}
+
+func (c *scramClient) Start() (mech string, ir []byte, err error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("sasl: generating %s nonce: %w", c.mech, err)
+	}
+	c.clientNonce = base64.StdEncoding.EncodeToString(nonce)
+	c.clientFirstBare = "n=" + scramEscape(c.username) + ",r=" + c.clientNonce
+	return c.mech, []byte("n,," + c.clientFirstBare), nil
+}
+
+func (c *scramClient) Next(challenge []byte) ([]byte, error) {
+	c.step++
+	switch c.step {
+	case 1:
+		return c.clientFinal(challenge)
+	case 2:
+		return c.checkServerFinal(challenge)
+	default:
+		return nil, fmt.Errorf("sasl: unexpected %s challenge", c.mech)
+	}
+}
+
+func (c *scramClient) clientFinal(serverFirst []byte) ([]byte, error) {
+	fields := parseScramFields(string(serverFirst))
+	nonce, salt, iters := fields["r"], fields["s"], fields["i"]
+	if nonce == "" || salt == "" || iters == "" || !strings.HasPrefix(nonce, c.clientNonce) {
+		return nil, fmt.Errorf("sasl: malformed %s server-first-message", c.mech)
+	}
+	saltRaw, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("sasl: decoding %s salt: %w", c.mech, err)
+	}
+	iterCount, err := strconv.Atoi(iters)
+	if err != nil {
+		return nil, fmt.Errorf("sasl: parsing %s iteration count: %w", c.mech, err)
+	}
+
+	c.saltedPassword = pbkdf2HMAC(c.newHash, []byte(c.password), saltRaw, iterCount)
+	clientKey := hmacSum(c.newHash, c.saltedPassword, []byte("Client Key"))
+	storedKey := hashSum(c.newHash, clientKey)
+
+	clientFinalNoProof := "c=biws,r=" + nonce
+	c.authMessage = c.clientFirstBare + "," + string(serverFirst) + "," + clientFinalNoProof
+	clientSignature := hmacSum(c.newHash, storedKey, []byte(c.authMessage))
+	proof := xorBytes(clientKey, clientSignature)
+
+	return []byte(clientFinalNoProof + ",p=" + base64.StdEncoding.EncodeToString(proof)), nil
+}
+
+func (c *scramClient) checkServerFinal(serverFinal []byte) ([]byte, error) {
+	fields := parseScramFields(string(serverFinal))
+	v := fields["v"]
+	if v == "" {
+		if e := fields["e"]; e != "" {
+			return nil, fmt.Errorf("sasl: %s authentication failed: %s", c.mech, e)
+		}
+		return nil, fmt.Errorf("sasl: malformed %s server-final-message", c.mech)
+	}
+	serverKey := hmacSum(c.newHash, c.saltedPassword, []byte("Server Key"))
+	serverSignature := hmacSum(c.newHash, serverKey, []byte(c.authMessage))
+	if v != base64.StdEncoding.EncodeToString(serverSignature) {
+		return nil, fmt.Errorf("sasl: %s server signature mismatch", c.mech)
+	}
+	return []byte{}, nil
+}
+
+func scramEscape(s string) string {
+	s = strings.ReplaceAll(s, "=", "=3D")
+	return strings.ReplaceAll(s, ",", "=2C")
+}
+
+func parseScramFields(s string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if i := strings.IndexByte(part, '='); i > 0 {
+			out[part[:i]] = part[i+1:]
+		}
+	}
+	return out
+}
+
+func hmacSum(newHash func() hash.Hash, key, msg []byte) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+func hashSum(newHash func() hash.Hash, data []byte) []byte {
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// pbkdf2HMAC implements the single-block case of RFC 2898 PBKDF2-HMAC that
+// SCRAM relies on (dkLen == the underlying hash size), avoiding a pull on
+// golang.org/x/crypto/pbkdf2 for one 15-line function.
+func pbkdf2HMAC(newHash func() hash.Hash, password, salt []byte, iter int) []byte {
+	mac := hmac.New(newHash, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	result := append([]byte{}, u...)
+	for i := 1; i < iter; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(nil)
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
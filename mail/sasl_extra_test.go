@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"crypto/sha1"
+	"testing"
+)
+
+// RFC 2195 section 3's worked CRAM-MD5 example.
+func TestCramMD5ClientNextMatchesRFC2195Example(t *testing.T) {
+	c := newCramMD5Client("tim", "tanstaaftanstaaf")
+
+	challenge := []byte("<1896.697170952@postoffice.reston.mci.net>")
+	resp, err := c.Next(challenge)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+
+	want := "tim b913a602c7eda7a495b4e6e7334d3890"
+	if string(resp) != want {
+		t.Errorf("response = %q, want %q", resp, want)
+	}
+}
+
+func TestXoauth2ClientStart(t *testing.T) {
+	c := newXoauth2Client("user@example.com", "token123")
+
+	mech, ir, err := c.Start()
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if mech != "XOAUTH2" {
+		t.Errorf("mech = %q, want XOAUTH2", mech)
+	}
+
+	want := "user=user@example.com\x01auth=Bearer token123\x01\x01"
+	if string(ir) != want {
+		t.Errorf("initial response = %q, want %q", ir, want)
+	}
+}
+
+func TestXoauth2ClientNextRepliesEmptyToErrorChallenge(t *testing.T) {
+	c := newXoauth2Client("user@example.com", "token123")
+	resp, err := c.Next([]byte(`{"status":"401","schemes":"bearer","scope":"mail"}`))
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if len(resp) != 0 {
+		t.Errorf("response = %q, want empty", resp)
+	}
+}
+
+// TestScramSHA1ClientMatchesRFC5802Example pins the client-final-message and
+// server-signature verification against RFC 5802 section 5's worked example,
+// with the client nonce fixed (rather than generated by Start) so the
+// expected bytes are reproducible.
+func TestScramSHA1ClientMatchesRFC5802Example(t *testing.T) {
+	c := &scramClient{
+		mech:            "SCRAM-SHA-1",
+		newHash:         sha1.New,
+		username:        "user",
+		password:        "pencil",
+		clientNonce:     "fyko+d2lbbFgONRv9qkxdawL",
+		clientFirstBare: "n=user,r=fyko+d2lbbFgONRv9qkxdawL",
+	}
+
+	serverFirst := "r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096"
+	resp, err := c.Next([]byte(serverFirst))
+	if err != nil {
+		t.Fatalf("Next(server-first): %v", err)
+	}
+
+	want := "c=biws,r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,p=v0X8v3Bz2T0CJGbJQyF0X+HI4Ts="
+	if string(resp) != want {
+		t.Fatalf("client-final-message = %q, want %q", resp, want)
+	}
+
+	serverFinal := "v=rmF9pqV8S7suAoZWja4dJRkFsKQ="
+	if _, err := c.Next([]byte(serverFinal)); err != nil {
+		t.Errorf("Next(server-final): %v", err)
+	}
+}
+
+func TestScramSHA1ClientRejectsBadServerSignature(t *testing.T) {
+	c := &scramClient{
+		mech:            "SCRAM-SHA-1",
+		newHash:         sha1.New,
+		username:        "user",
+		password:        "pencil",
+		clientNonce:     "fyko+d2lbbFgONRv9qkxdawL",
+		clientFirstBare: "n=user,r=fyko+d2lbbFgONRv9qkxdawL",
+	}
+
+	serverFirst := "r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096"
+	if _, err := c.Next([]byte(serverFirst)); err != nil {
+		t.Fatalf("Next(server-first): %v", err)
+	}
+
+	if _, err := c.Next([]byte("v=AAAAAAAAAAAAAAAAAAAAAAAAAAA=")); err == nil {
+		t.Fatal("Next(server-final) accepted a forged server signature")
+	}
+}
+
+func TestScramSHA1ClientRejectsNonceMismatch(t *testing.T) {
+	c := &scramClient{
+		mech:            "SCRAM-SHA-1",
+		newHash:         sha1.New,
+		username:        "user",
+		password:        "pencil",
+		clientNonce:     "fyko+d2lbbFgONRv9qkxdawL",
+		clientFirstBare: "n=user,r=fyko+d2lbbFgONRv9qkxdawL",
+	}
+
+	if _, err := c.Next([]byte("r=not-our-nonce,s=QSXCR+Q6sek8bf92,i=4096")); err == nil {
+		t.Fatal("Next accepted a server-first-message whose nonce doesn't extend ours")
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+// TestSignSESRequestMatchesKnownVector pins signSESRequest's canonical
+// request / string-to-sign / signature computation against a fixed
+// time/key/body, rather than only exercising it end-to-end against a fake
+// server (where a self-consistent but wrong implementation would still
+// "pass").
+func TestSignSESRequestMatchesKnownVector(t *testing.T) {
+	cfg := config.SESConfig{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+	}
+	body := []byte(`{"hello":"world"}`)
+	req, err := http.NewRequest(http.MethodPost, "https://email.us-east-1.amazonaws.com/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	signSESRequest(req, body, cfg, now)
+
+	if got, want := req.Header.Get("X-Amz-Date"), "20240101T000000Z"; got != want {
+		t.Errorf("X-Amz-Date = %q, want %q", got, want)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240101/us-east-1/ses/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=d88021ebfed95160d3d3c639343b272d6b4769de34f70331fef510fb59c82f51"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+}
+
+func TestSESTransportSendsExpectedRequest(t *testing.T) {
+	var gotMethod, gotPath, gotAuth, gotContentType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport, err := NewSESTransport(config.SESConfig{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		Region:          "us-east-1",
+		BaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewSESTransport: %v", err)
+	}
+
+	msg := &Message{
+		From:    "noreply@example.com",
+		To:      []string{"a@example.com"},
+		Subject: "Hi",
+		HTML:    "<p>hi</p>",
+	}
+	if err := transport.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if gotMethod != http.MethodPost {
+		t.Errorf("method = %q, want POST", gotMethod)
+	}
+	if gotPath != "/v2/email/outbound-emails" {
+		t.Errorf("path = %q, want /v2/email/outbound-emails", gotPath)
+	}
+	if gotContentType != "application/json" {
+		t.Errorf("content-type = %q, want application/json", gotContentType)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		t.Errorf("Authorization = %q, missing SigV4 credential prefix", gotAuth)
+	}
+
+	var payload struct {
+		FromEmailAddress string `json:"FromEmailAddress"`
+		Destination      struct {
+			ToAddresses []string `json:"ToAddresses"`
+		} `json:"Destination"`
+		Content struct {
+			Simple struct {
+				Subject struct {
+					Data string `json:"Data"`
+				} `json:"Subject"`
+			} `json:"Simple"`
+		} `json:"Content"`
+	}
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("unmarshal request body: %v", err)
+	}
+	if payload.FromEmailAddress != "noreply@example.com" {
+		t.Errorf("FromEmailAddress = %q, want noreply@example.com", payload.FromEmailAddress)
+	}
+	if !reflect.DeepEqual(payload.Destination.ToAddresses, []string{"a@example.com"}) {
+		t.Errorf("ToAddresses = %v, want [a@example.com]", payload.Destination.ToAddresses)
+	}
+	if payload.Content.Simple.Subject.Data != "Hi" {
+		t.Errorf("Subject.Data = %q, want Hi", payload.Content.Simple.Subject.Data)
+	}
+}
+
+func TestSESTransportRejectsNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad region", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	transport, err := NewSESTransport(config.SESConfig{
+		AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "us-east-1", BaseURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewSESTransport: %v", err)
+	}
+	if err := transport.Send(&Message{From: "a@example.com", To: []string{"b@example.com"}}); err == nil {
+		t.Fatal("Send succeeded, want error on 403")
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"os"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+//go:embed templates/default_subject.tmpl templates/default_body.html.tmpl
+var defaultTemplatesFS embed.FS
+
+// TemplateData is the context exposed to subject/body templates, e.g.
+// `{{ .Payload.name }}`, `{{ .RemoteIP }}` or `{{ .Now | date "2006-01-02" }}`.
+type TemplateData struct {
+	Payload  map[string]interface{}
+	Rows     []TableRow // sanitized, sorted Payload entries; used by the default table templates
+	RemoteIP string
+	Now      time.Time
+}
+
+// TemplateSet holds the parsed subject/HTML/text templates used to render
+// an outgoing Message.
+type TemplateSet struct {
+	subject      *template.Template
+	html         *template.Template
+	text         *texttemplate.Template        // nil unless a text template was configured; text/template, not html/template, so it doesn't HTML-escape the plain-text part
+	formSubjects map[string]*template.Template // per-form subject overrides, keyed by form ID
+}
+
+// LoadTemplates parses the templates referenced by cfg.Mail, plus any
+// per-form SubjectTemplate overrides in cfg.Forms. SubjectTemplate and
+// HTMLTemplate fall back to the embedded table-based defaults when unset;
+// TextTemplate has no default and is only rendered (producing a
+// multipart/alternative message) when configured.
+func LoadTemplates(cfg config.Config) (*TemplateSet, error) {
+	subject, err := parseTemplate("subject", cfg.Mail.SubjectTemplate, "templates/default_subject.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	html, err := parseTemplate("html", cfg.Mail.HTMLTemplate, "templates/default_body.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TemplateSet{subject: subject, html: html}
+
+	if cfg.Mail.TextTemplate != "" {
+		text, err := parseTextTemplate("text", cfg.Mail.TextTemplate)
+		if err != nil {
+			return nil, err
+		}
+		ts.text = text
+	}
+
+	for formID, form := range cfg.Forms {
+		if form.SubjectTemplate == "" {
+			continue
+		}
+		tmpl, err := parseTemplate("subject:"+formID, form.SubjectTemplate, "")
+		if err != nil {
+			return nil, err
+		}
+		if ts.formSubjects == nil {
+			ts.formSubjects = make(map[string]*template.Template)
+		}
+		ts.formSubjects[formID] = tmpl
+	}
+
+	return ts, nil
+}
+
+func parseTemplate(name, path, defaultAsset string) (*template.Template, error) {
+	var src []byte
+	var err error
+	if path != "" {
+		src, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("mail: reading %s template %s: %w", name, path, err)
+		}
+	} else {
+		src, err = defaultTemplatesFS.ReadFile(defaultAsset)
+		if err != nil {
+			return nil, fmt.Errorf("mail: reading embedded %s template: %w", name, err)
+		}
+	}
+
+	tmpl, err := template.New(name).Funcs(sprig.FuncMap()).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("mail: parsing %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// parseTextTemplate reads and parses the text/plain alternative template at
+// path with text/template rather than html/template, so values like "Tom &
+// Jerry" render verbatim instead of HTML-entity-escaped. There is no
+// embedded default for it; it is only ever called when path is set.
+func parseTextTemplate(name, path string) (*texttemplate.Template, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mail: reading %s template %s: %w", name, path, err)
+	}
+	tmpl, err := texttemplate.New(name).Funcs(sprig.FuncMap()).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("mail: parsing %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Render executes the template set against data, producing the subject, HTML
+// body and (optional) text body for a Message. formID selects a per-form
+// subject override from ts.formSubjects, falling back to the default subject
+// template when formID is empty or has no override.
+func (ts *TemplateSet) Render(formID string, data TemplateData) (subject, html, text string, err error) {
+	var sb, hb, tb bytes.Buffer
+
+	subjectTmpl := ts.subject
+	if tmpl, ok := ts.formSubjects[formID]; ok {
+		subjectTmpl = tmpl
+	}
+	if err := subjectTmpl.Execute(&sb, data); err != nil {
+		return "", "", "", fmt.Errorf("mail: rendering subject template: %w", err)
+	}
+	if err := ts.html.Execute(&hb, data); err != nil {
+		return "", "", "", fmt.Errorf("mail: rendering html template: %w", err)
+	}
+	if ts.text != nil {
+		if err := ts.text.Execute(&tb, data); err != nil {
+			return "", "", "", fmt.Errorf("mail: rendering text template: %w", err)
+		}
+	}
+
+	return sb.String(), hb.String(), tb.String(), nil
+}
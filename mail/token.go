@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+// resolveToken fetches a bearer token for the "xoauth2"/"oauthbearer" SASL
+// mechanisms. Static, Command and URL are tried in that order; the first one
+// configured wins.
+func resolveToken(cfg config.TokenSourceConfig) (string, error) {
+	if cfg.Static != "" {
+		return cfg.Static, nil
+	}
+	if cfg.Command != "" {
+		out, err := exec.Command("/bin/sh", "-c", cfg.Command).Output()
+		if err != nil {
+			return "", fmt.Errorf("token command failed: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	if cfg.URL != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(cfg.URL)
+		if err != nil {
+			return "", fmt.Errorf("token endpoint request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("token endpoint read failed: %w", err)
+		}
+		return strings.TrimSpace(string(body)), nil
+	}
+	return "", fmt.Errorf("no token source configured")
+}
@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package mail
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+// Message is a transport-agnostic representation of an outgoing email. It is
+// built once by BuildMessage and handed to whichever Transport the operator
+// has configured.
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string // envelope recipients only; never appears in RawMessage's headers
+	Subject string
+	HTML    string
+	Text    string // optional text/plain alternative; when set, RawMessage emits multipart/alternative
+}
+
+// Recipients returns every address that should receive the message at the
+// protocol/envelope level: To, Cc and Bcc combined.
+func (m *Message) Recipients() []string {
+	return append(append(append([]string{}, m.To...), m.Cc...), m.Bcc...)
+}
+
+// Transport delivers a Message. ctaHandler only ever talks to a Transport, so
+// the concrete delivery mechanism (SMTP or one of the HTTP-API providers) can
+// be swapped without touching the handler, and tests can inject a fake.
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// NewTransport builds the Transport selected by cfg.Mail.Backend, defaulting
+// to "smtp" (using the top-level SMTP block) when unset, for backward
+// compatibility with configs written before the HTTP-API backends existed.
+func NewTransport(cfg config.Config) (Transport, error) {
+	switch strings.ToLower(cfg.Mail.Backend) {
+	case "", "smtp":
+		return NewSMTPTransport(cfg.SMTP)
+	case "mailgun":
+		return NewMailgunTransport(cfg.Mail.Mailgun)
+	case "sendgrid":
+		return NewSendGridTransport(cfg.Mail.SendGrid)
+	case "ses":
+		return NewSESTransport(cfg.Mail.SES)
+	default:
+		return nil, fmt.Errorf("mail: unsupported backend %q", cfg.Mail.Backend)
+	}
+}
+
+// smtpTransport delivers messages over SMTP using go-smtp, authenticating
+// with whichever SASL mechanism the config requests.
+type smtpTransport struct {
+	cfg config.SMTPConfig
+}
+
+// NewSMTPTransport builds a Transport backed by the given SMTP config. It
+// does not dial the server; connections are established per-message.
+func NewSMTPTransport(cfg config.SMTPConfig) (Transport, error) {
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("mail: smtp server is not configured")
+	}
+	return &smtpTransport{cfg: cfg}, nil
+}
+
+func (t *smtpTransport) Send(msg *Message) error {
+	cfg := t.cfg
+	addr := fmt.Sprintf("%s:%d", cfg.Server, cfg.Port)
+	raw := RawMessage(msg)
+
+	var c *smtp.Client
+	var err error
+
+	switch parseEncryption(cfg.Encryption) {
+	case encSSL:
+		tlsconf := &tls.Config{InsecureSkipVerify: !cfg.VerifyTLS, ServerName: cfg.Server}
+		c, err = smtp.DialTLS(addr, tlsconf)
+	case encStartTLS:
+		tlsconf := &tls.Config{InsecureSkipVerify: !cfg.VerifyTLS, ServerName: cfg.Server}
+		c, err = smtp.DialStartTLS(addr, tlsconf)
+	default:
+		c, err = smtp.Dial(addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if cfg.Username != "" || cfg.AuthMechanism != "" {
+		auth, err := saslClient(cfg)
+		if err != nil {
+			return err
+		}
+		if auth != nil {
+			if err := c.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Mail(msg.From, nil); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := c.Rcpt(rcpt, nil); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+type encryptionMode int
+
+const (
+	encPlain encryptionMode = iota
+	encSSL
+	encStartTLS
+)
+
+func parseEncryption(s string) encryptionMode {
+	switch strings.ToLower(s) {
+	case "ssl":
+		return encSSL
+	case "starttls":
+		return encStartTLS
+	default:
+		return encPlain
+	}
+}
+
+// saslClient builds the go-sasl client for cfg.AuthMechanism. An empty
+// mechanism defaults to PLAIN for backward compatibility with the previous
+// net/smtp-based transport.
+func saslClient(cfg config.SMTPConfig) (sasl.Client, error) {
+	switch strings.ToLower(cfg.AuthMechanism) {
+	case "", "plain":
+		return sasl.NewPlainClient("", cfg.Username, cfg.Password), nil
+	case "login":
+		return sasl.NewLoginClient(cfg.Username, cfg.Password), nil
+	case "cram-md5":
+		return newCramMD5Client(cfg.Username, cfg.Password), nil
+	case "scram-sha-1":
+		return newScramClient("SCRAM-SHA-1", sha1.New, cfg.Username, cfg.Password), nil
+	case "scram-sha-256":
+		return newScramClient("SCRAM-SHA-256", sha256.New, cfg.Username, cfg.Password), nil
+	case "xoauth2":
+		token, err := resolveToken(cfg.TokenSource)
+		if err != nil {
+			return nil, fmt.Errorf("mail: resolving xoauth2 token: %w", err)
+		}
+		return newXoauth2Client(cfg.Username, token), nil
+	case "oauthbearer":
+		token, err := resolveToken(cfg.TokenSource)
+		if err != nil {
+			return nil, fmt.Errorf("mail: resolving oauthbearer token: %w", err)
+		}
+		return sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: cfg.Username, Token: token}), nil
+	default:
+		return nil, fmt.Errorf("mail: unsupported auth mechanism %q", cfg.AuthMechanism)
+	}
+}
+
+// RawMessage renders msg as an RFC 5322 message, ready to hand to an SMTP
+// DATA command or print for --dry-run. It emits a single text/html part, or
+// a multipart/alternative (text/plain + text/html) part when msg.Text is
+// set.
+func RawMessage(msg *Message) []byte {
+	headers := "From: " + msg.From + "\r\n" +
+		"To: " + joinAddrs(msg.To) + "\r\n"
+	if len(msg.Cc) > 0 {
+		headers += "Cc: " + joinAddrs(msg.Cc) + "\r\n"
+	}
+	headers += "Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n"
+
+	if msg.Text == "" {
+		return []byte(headers + "Content-Type: text/html; charset=UTF-8\r\n\r\n" + msg.HTML)
+	}
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	b.WriteString(headers)
+	b.WriteString("Content-Type: multipart/alternative; boundary=" + mw.Boundary() + "\r\n\r\n")
+
+	writePart(mw, "text/plain; charset=UTF-8", msg.Text)
+	writePart(mw, "text/html; charset=UTF-8", msg.HTML)
+	mw.Close()
+
+	return b.Bytes()
+}
+
+func writePart(mw *multipart.Writer, contentType, body string) {
+	w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return
+	}
+	w.Write([]byte(body))
+}
+
+func joinAddrs(addrs []string) string {
+	out := ""
+	for i, a := range addrs {
+		if i > 0 {
+			out += ", "
+		}
+		out += a
+	}
+	return out
+}
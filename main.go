@@ -9,36 +9,38 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
-	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	config "github.com/matteoragni/website-backend/config"
 	mail "github.com/matteoragni/website-backend/mail"
+	ratelimit "github.com/matteoragni/website-backend/ratelimit"
+	signer "github.com/matteoragni/website-backend/signer"
 	turnstile "github.com/matteoragni/website-backend/turnstile"
 )
 
-// rate limiter
-type rateInfo struct {
-	timestamps []time.Time
-}
-
 var (
-	cfg    config.Config            // global configuration
-	rlMu   sync.Mutex               // rate limiter mutex
-	rlimap = map[string]*rateInfo{} // rate limiter database
+	cfg            config.Config      // global configuration
+	transport      mail.Transport     // mail delivery backend
+	templates      *mail.TemplateSet  // parsed subject/body templates
+	rateLimiter    *ratelimit.Limiter // leaky-bucket rate limiter
+	responseSigner *signer.Signer     // signs return_url redirects, nil if not configured
+	ctaEndpoint    string             // path ctaHandler is registered on; form IDs may be appended as a suffix
 )
 
 func main() {
 	cfgFile := flag.String("config", "config.json", "path to JSON config")
 	addr := flag.String("listen", ":8080", "listen address")
+	dryRun := flag.String("dry-run", "", "path to a JSON payload file; render the mail templates against it and print the resulting MIME message instead of sending or serving")
 	flag.Parse()
 
 	var err error
@@ -48,8 +50,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	templates, err = mail.LoadTemplates(cfg)
+	if err != nil {
+		_, _ = os.Stderr.WriteString("mail template setup failed: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+
+	if *dryRun != "" {
+		runDryRun(*dryRun)
+		return
+	}
+
 	setupLogger(cfg.Log)
 
+	transport, err = mail.NewTransport(cfg)
+	if err != nil {
+		log.Fatalf("mail transport setup failed: %v", err)
+	}
+
+	store, err := ratelimit.NewStoreFromConfig(cfg.Options)
+	if err != nil {
+		log.Fatalf("rate limit store setup failed: %v", err)
+	}
+	rateLimiter = ratelimit.NewLimiter(cfg.Options.RateLimitBuckets, store)
+
+	if cfg.Options.ResponseSigningKey != "" {
+		responseSigner, err = signer.LoadFromFile(cfg.Options.ResponseSigningKey)
+		if err != nil {
+			log.Fatalf("response signing key setup failed: %v", err)
+		}
+	}
+
 	mux := http.NewServeMux()
 
 	// static locations
@@ -90,11 +121,12 @@ func main() {
 		}
 	}
 
-	ctaEndpoint := cfg.Options.CTAEndpoint
+	ctaEndpoint = cfg.Options.CTAEndpoint
 	if ctaEndpoint == "" {
 		ctaEndpoint = "/-/cta"
 	}
 	mux.HandleFunc(ctaEndpoint, ctaHandler)
+	mux.HandleFunc(ctaEndpoint+"/", ctaHandler)
 
 	srv := &http.Server{Addr: *addr, Handler: limitMiddleware(mux)}
 	log.Infof("listening %s", *addr)
@@ -103,6 +135,32 @@ func main() {
 	}
 }
 
+// runDryRun renders the configured mail templates against the JSON payload
+// at path and prints the resulting MIME message to stdout, without sending
+// it or starting the server.
+func runDryRun(path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("dry-run: reading payload: %v", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		log.Fatalf("dry-run: parsing payload: %v", err)
+	}
+
+	formID, _ := payload["form"].(string)
+	msg, err := mail.BuildMessage(templates, cfg, formID, mail.TemplateData{
+		Payload:  payload,
+		RemoteIP: "127.0.0.1",
+		Now:      time.Now(),
+	})
+	if err != nil {
+		log.Fatalf("dry-run: rendering templates: %v", err)
+	}
+
+	os.Stdout.Write(mail.RawMessage(msg))
+}
+
 func setupLogger(l config.LogConfig) {
 	level, err := log.ParseLevel(strings.ToLower(l.MinLevel))
 	if err != nil {
@@ -129,6 +187,16 @@ func limitMiddleware(next http.Handler) http.Handler {
 			http.Error(w, "invalid request", http.StatusBadRequest)
 			return
 		}
+
+		if cfg.Options.EnableRateLimiting {
+			if allowed, retryAfter := rateLimiter.Allow(r); !allowed {
+				warnRefuse(r, "rate limit")
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
+				http.Error(w, "invalid request", http.StatusTooManyRequests)
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -147,21 +215,17 @@ func ctaHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// rate limit by IP
-	ip := clientIP(r)
-	if !allowRate(ip) {
-		warnRefuse(r, "rate limit")
-		http.Error(w, "invalid request", http.StatusTooManyRequests)
-		return
-	}
+	ip := ratelimit.ClientIP(r)
 
 	// enforce max bytes
 	r.Body = http.MaxBytesReader(w, r.Body, cfg.Options.MaxBodySize)
 	defer r.Body.Close()
 
 	var body struct {
-		Token   string                 `json:"token"`
-		Payload map[string]interface{} `json:"payload"`
+		Token     string                 `json:"token"`
+		Form      string                 `json:"form"`
+		Payload   map[string]interface{} `json:"payload"`
+		ReturnURL string                 `json:"return_url"`
 	}
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&body); err != nil && err != io.EOF {
@@ -170,88 +234,142 @@ func ctaHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	formID := body.Form
+	if suffix := strings.TrimPrefix(r.URL.Path, ctaEndpoint); suffix != r.URL.Path {
+		if trimmed := strings.Trim(suffix, "/"); trimmed != "" {
+			formID = trimmed
+		}
+	}
+	if len(cfg.Forms) > 0 {
+		if _, ok := cfg.Forms[formID]; !ok {
+			warnRefuseForm(r, formID, "unknown form")
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+
 	if body.Token == "" {
-		warnRefuse(r, "missing token")
+		warnRefuseForm(r, formID, "missing token")
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
 	ok, err := turnstile.VerifyTurnstile(cfg.CFTurn, body.Token, ip)
 	if err != nil || !ok {
-		warnRefuse(r, "turnstile failed: "+err.Error())
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		refuseOrRedirect(w, r, formID, body.ReturnURL, "turnstile failed", http.StatusBadRequest)
 		return
 	}
 
-	// build sanitized email body
-	if err := mail.SendMail(cfg.SMTP, "New CTA Submission", body.Payload); err != nil {
+	// render the configured mail templates and hand the message to the transport
+	msg, err := mail.BuildMessage(templates, cfg, formID, mail.TemplateData{
+		Payload:  body.Payload,
+		RemoteIP: ip,
+		Now:      time.Now(),
+	})
+	if err != nil {
+		log.Warnf("render mail failed: %v", err)
+		refuseOrRedirect(w, r, formID, body.ReturnURL, "mail render failed", http.StatusInternalServerError)
+		return
+	}
+	if err := transport.Send(msg); err != nil {
 		log.Warnf("send mail failed: %v", err)
-		http.Error(w, "invalid request", http.StatusInternalServerError)
+		refuseOrRedirect(w, r, formID, body.ReturnURL, "mail send failed", http.StatusInternalServerError)
 		return
 	}
 
+	if responseSigner != nil {
+		if dest, ok := sanitizeReturnURL(body.ReturnURL); ok {
+			redirectSigned(w, r, dest, body.ReturnURL, "ok", "")
+			return
+		}
+		if body.ReturnURL != "" {
+			warnRefuseForm(r, formID, "disallowed return_url")
+		}
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func isFakeUA(ua string) bool {
-	if !cfg.Options.BlockBotUserAgents {
-		return false
-	}
-	l := strings.ToLower(ua)
-	if strings.Contains(l, "curl/") || strings.Contains(l, "python-requests") || strings.Contains(l, "bot") {
-		return true
+// refuseOrRedirect logs the refusal and either 302s the browser back to a
+// signed return_url (when a signer is configured and returnURL passes
+// sanitizeReturnURL) or falls back to a plain error response.
+func refuseOrRedirect(w http.ResponseWriter, r *http.Request, formID, returnURL, reason string, status int) {
+	warnRefuseForm(r, formID, reason)
+	if responseSigner != nil {
+		if dest, ok := sanitizeReturnURL(returnURL); ok {
+			redirectSigned(w, r, dest, returnURL, "error", reason)
+			return
+		}
 	}
-	return false
+	http.Error(w, "invalid request", status)
 }
 
-func clientIP(r *http.Request) string {
-	// prefer X-Forwarded-For
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
+// sanitizeReturnURL reports whether raw is safe to redirect a browser to:
+// either host-relative (no scheme/host, so it necessarily targets this
+// backend's own origin) or absolute with a host present in
+// cfg.Options.AllowedReturnHosts. Anything else - including scheme-relative
+// URLs like "//evil.example/x", which parse with an empty scheme but a
+// non-empty host - is rejected.
+func sanitizeReturnURL(raw string) (*url.URL, bool) {
+	if raw == "" {
+		return nil, false
 	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	dest, err := url.Parse(raw)
 	if err != nil {
-		return r.RemoteAddr
+		return nil, false
 	}
-	return host
+	if dest.Host == "" && dest.Scheme == "" {
+		return dest, true
+	}
+	for _, host := range cfg.Options.AllowedReturnHosts {
+		if strings.EqualFold(dest.Host, host) {
+			return dest, true
+		}
+	}
+	return nil, false
 }
 
-func allowRate(ip string) bool {
-	if !cfg.Options.EnableRateLimiting {
-		return true
+// redirectSigned 302s to dest with its query string replaced by
+// status/reason/ts/dest/sig params, the last a base64 signature over the
+// canonicalised query string; a query the return_url carried survives only
+// inside the dest param. Signing dest (rawReturnURL, i.e. the destination verbatim as the
+// caller supplied it) binds the signature to that exact destination, so a
+// captured status=ok signature can't be replayed against a different
+// return_url even when both hosts are allowlisted.
+func redirectSigned(w http.ResponseWriter, r *http.Request, dest *url.URL, rawReturnURL, status, reason string) {
+	params := url.Values{
+		"status": {status},
+		"reason": {reason},
+		"ts":     {strconv.FormatInt(time.Now().Unix(), 10)},
+		"dest":   {rawReturnURL},
 	}
+	params.Set("sig", responseSigner.Sign(params))
 
-	const limit = 5            // TODO: This should be configurable
-	const window = time.Minute // TODO: This should be configurable
-
-	rlMu.Lock()
-	defer rlMu.Unlock()
+	dest.RawQuery = params.Encode()
+	http.Redirect(w, r, dest.String(), http.StatusFound)
+}
 
-	now := time.Now()
-	ri, ok := rlimap[ip]
-	if !ok {
-		ri = &rateInfo{timestamps: []time.Time{now}}
-		rlimap[ip] = ri
-		return true
-	}
-	// prune
-	t := ri.timestamps
-	var nt []time.Time
-	for _, ts := range t {
-		if now.Sub(ts) <= window {
-			nt = append(nt, ts)
-		}
-	}
-	nt = append(nt, now)
-	ri.timestamps = nt
-	if len(nt) > limit {
+func isFakeUA(ua string) bool {
+	if !cfg.Options.BlockBotUserAgents {
 		return false
 	}
-	return true
+	l := strings.ToLower(ua)
+	if strings.Contains(l, "curl/") || strings.Contains(l, "python-requests") || strings.Contains(l, "bot") {
+		return true
+	}
+	return false
 }
 
 func warnRefuse(r *http.Request, reason string) {
-	ip := clientIP(r)
-	log.WithFields(log.Fields{"ip": ip, "path": r.URL.Path, "reason": reason}).Warn("refused submit")
+	warnRefuseForm(r, "", reason)
+}
+
+// warnRefuseForm logs a refusal with the resolved form ID, when known, so
+// operators can audit which form's submissions are being rejected.
+func warnRefuseForm(r *http.Request, formID, reason string) {
+	ip := ratelimit.ClientIP(r)
+	fields := log.Fields{"ip": ip, "path": r.URL.Path, "reason": reason}
+	if formID != "" {
+		fields["form"] = formID
+	}
+	log.WithFields(fields).Warn("refused submit")
 }
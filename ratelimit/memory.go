@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type bucketState struct {
+	level    float64
+	leakRate float64
+	lastSeen time.Time
+}
+
+// MemoryStore is the default in-process RateStore. It is safe for
+// concurrent use; a background goroutine periodically evicts buckets that
+// have fully leaked so the map doesn't grow without bound.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state map[string]*bucketState
+	stop  chan struct{}
+}
+
+// NewMemoryStore builds a MemoryStore whose eviction sweep runs every
+// evictInterval (defaulting to one minute when <= 0).
+func NewMemoryStore(evictInterval time.Duration) *MemoryStore {
+	if evictInterval <= 0 {
+		evictInterval = time.Minute
+	}
+	s := &MemoryStore{state: map[string]*bucketState{}, stop: make(chan struct{})}
+	go s.evictLoop(evictInterval)
+	return s
+}
+
+// Close stops the background eviction goroutine.
+func (s *MemoryStore) Close() {
+	close(s.stop)
+}
+
+func (s *MemoryStore) Take(key string, capacity, leakRate float64, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.state[key]
+	if !ok {
+		b = &bucketState{lastSeen: now}
+		s.state[key] = b
+	}
+
+	level := leak(b, now)
+
+	if level+1 > capacity {
+		b.level = level
+		b.leakRate = leakRate
+		b.lastSeen = now
+		return false, retryAfterFor(level, capacity, leakRate)
+	}
+
+	b.level = level + 1
+	b.leakRate = leakRate
+	b.lastSeen = now
+	return true, 0
+}
+
+// retryAfterFor computes how long the caller should wait before the bucket
+// has room again. config.LoadConfig rejects RateLimitBucket.LeakRate <= 0,
+// but this guards the division anyway: without it, a misconfigured bucket
+// that slipped through (or a direct RateStore.Take caller) would produce a
+// NaN or negative Retry-After instead of a sane fallback.
+func retryAfterFor(level, capacity, leakRate float64) time.Duration {
+	if leakRate <= 0 {
+		return time.Minute
+	}
+	return time.Duration((level + 1 - capacity) / leakRate * float64(time.Second))
+}
+
+// leak computes b's level as of now, given how much time has passed since
+// it was last touched at its last known leak rate.
+func leak(b *bucketState, now time.Time) float64 {
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	level := b.level - b.leakRate*elapsed
+	if level < 0 {
+		level = 0
+	}
+	return level
+}
+
+func (s *MemoryStore) evictLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.evict()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) evict() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, b := range s.state {
+		if leak(b, now) <= 0 {
+			delete(s.state, k)
+		}
+	}
+}
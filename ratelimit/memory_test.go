@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTakeWithinCapacity(t *testing.T) {
+	s := &MemoryStore{state: map[string]*bucketState{}}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		allowed, retryAfter := s.Take("k", 3, 1, now)
+		if !allowed {
+			t.Fatalf("request %d: got allowed=false, want true", i)
+		}
+		if retryAfter != 0 {
+			t.Fatalf("request %d: got retryAfter=%v, want 0", i, retryAfter)
+		}
+	}
+}
+
+func TestMemoryStoreTakeOverCapacityReportsRetryAfter(t *testing.T) {
+	s := &MemoryStore{state: map[string]*bucketState{}}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := s.Take("k", 2, 1, now); !allowed {
+			t.Fatalf("request %d: got allowed=false, want true", i)
+		}
+	}
+
+	allowed, retryAfter := s.Take("k", 2, 1, now)
+	if allowed {
+		t.Fatal("got allowed=true over capacity, want false")
+	}
+	if retryAfter != time.Second {
+		t.Fatalf("got retryAfter=%v, want 1s", retryAfter)
+	}
+}
+
+func TestMemoryStoreTakeLeaksOverTime(t *testing.T) {
+	s := &MemoryStore{state: map[string]*bucketState{}}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := s.Take("k", 2, 1, now); !allowed {
+			t.Fatalf("request %d: got allowed=false, want true", i)
+		}
+	}
+
+	// Leak rate is 1 token/sec; after 2s the bucket should have room again.
+	later := now.Add(2 * time.Second)
+	if allowed, retryAfter := s.Take("k", 2, 1, later); !allowed {
+		t.Fatalf("got allowed=false after leak, want true (retryAfter=%v)", retryAfter)
+	}
+}
+
+func TestMemoryStoreTakeZeroLeakRateDoesNotDivideByZero(t *testing.T) {
+	s := &MemoryStore{state: map[string]*bucketState{}}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := s.Take("k", 3, 0, now); !allowed {
+			t.Fatalf("request %d: got allowed=false, want true", i)
+		}
+	}
+
+	// A LeakRate of 0 never drains the bucket, even a day later; the
+	// important thing is that Retry-After comes back sane rather than the
+	// NaN/negative duration a naive (level+1-capacity)/0 would produce.
+	allowed, retryAfter := s.Take("k", 3, 0, now.Add(24*time.Hour))
+	if allowed {
+		t.Fatal("got allowed=true with leakRate=0, want false (bucket never drains)")
+	}
+	if retryAfter <= 0 {
+		t.Errorf("got retryAfter=%v, want a positive fallback duration", retryAfter)
+	}
+}
+
+func TestMemoryStoreEvictRemovesDrainedBuckets(t *testing.T) {
+	// evict() reads time.Now() internally, so this test is anchored to real
+	// wall-clock time rather than an arbitrary fixed instant.
+	s := &MemoryStore{state: map[string]*bucketState{}}
+	now := time.Now()
+
+	s.Take("drained", 1, 1, now)
+	s.Take("still-full", 100, 1, now)
+
+	// Backdate "drained"'s last-seen far enough that, at leakRate 1/sec, it
+	// has fully leaked by the time evict() runs; "still-full" (level 1 of
+	// 100 capacity) is left untouched and should survive.
+	s.mu.Lock()
+	s.state["drained"].lastSeen = now.Add(-10 * time.Second)
+	s.mu.Unlock()
+
+	s.evict()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.state["drained"]; ok {
+		t.Error("expected \"drained\" bucket to be evicted")
+	}
+	if _, ok := s.state["still-full"]; !ok {
+		t.Error("expected \"still-full\" bucket to survive eviction")
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+// RateStore tracks leaky-bucket levels keyed by an arbitrary string, so that
+// multiple backend replicas can share rate-limit state. Take must be safe
+// for concurrent use.
+type RateStore interface {
+	// Take attempts to consume one token from the bucket identified by key,
+	// leaking at leakRate tokens/sec up to capacity tokens, as of now. It
+	// reports whether the request is allowed and, if not, how long the
+	// caller should wait before the bucket has room again.
+	Take(key string, capacity, leakRate float64, now time.Time) (allowed bool, retryAfter time.Duration)
+}
+
+// Limiter enforces a list of leaky buckets against incoming requests, each
+// scoped to requests whose path starts with the bucket's PathPrefix.
+type Limiter struct {
+	buckets []config.RateLimitBucket
+	store   RateStore
+}
+
+// NewLimiter builds a Limiter for buckets, backed by store.
+func NewLimiter(buckets []config.RateLimitBucket, store RateStore) *Limiter {
+	return &Limiter{buckets: buckets, store: store}
+}
+
+// Allow reports whether r is allowed through, and if not, how long the
+// caller should wait before retrying (suitable for a Retry-After header). A
+// path matching no configured bucket is always allowed.
+func (l *Limiter) Allow(r *http.Request) (allowed bool, retryAfter time.Duration) {
+	bucket, ok := l.match(r.URL.Path)
+	if !ok {
+		return true, 0
+	}
+	key := bucket.PathPrefix + "|" + bucketKey(bucket, r)
+	return l.store.Take(key, bucket.Capacity, bucket.LeakRate, time.Now())
+}
+
+// match picks the bucket whose PathPrefix matches path, preferring the
+// longest prefix when several do.
+func (l *Limiter) match(path string) (config.RateLimitBucket, bool) {
+	var best config.RateLimitBucket
+	found := false
+	for _, b := range l.buckets {
+		if strings.HasPrefix(path, b.PathPrefix) && (!found || len(b.PathPrefix) > len(best.PathPrefix)) {
+			best = b
+			found = true
+		}
+	}
+	return best, found
+}
+
+func bucketKey(bucket config.RateLimitBucket, r *http.Request) string {
+	ip := ClientIP(r)
+	switch {
+	case strings.HasPrefix(bucket.KeyBy, "header:"):
+		name := strings.TrimPrefix(bucket.KeyBy, "header:")
+		return ip + "|" + r.Header.Get(name)
+	case bucket.KeyBy == "ip+ua":
+		return ip + "|" + r.Header.Get("User-Agent")
+	default: // "", "ip"
+		return ip
+	}
+}
+
+// ClientIP extracts the caller's IP from r, preferring X-Forwarded-For.
+func ClientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		return strings.TrimSpace(parts[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	config "github.com/matteoragni/website-backend/config"
+)
+
+// NewStoreFromConfig builds the RateStore selected by opts.RateLimitStore,
+// defaulting to an in-process MemoryStore when unset. newRedisStoreFromConfig
+// is provided by redis.go or store_noredis.go depending on whether the
+// binary was built with -tags redis.
+func NewStoreFromConfig(opts config.OptionsConfig) (RateStore, error) {
+	switch strings.ToLower(opts.RateLimitStore) {
+	case "", "memory":
+		return NewMemoryStore(time.Minute), nil
+	case "redis":
+		return newRedisStoreFromConfig(opts.RateLimitRedisAddr)
+	default:
+		return nil, fmt.Errorf("ratelimit: unsupported rate limit store %q", opts.RateLimitStore)
+	}
+}
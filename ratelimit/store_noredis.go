@@ -0,0 +1,18 @@
+//go:build !redis
+
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import "fmt"
+
+// newRedisStoreFromConfig backs NewStoreFromConfig's "redis" case in builds
+// that don't include redis.go: selecting it is a configuration error rather
+// than a silent fallback, so operators notice they need -tags redis.
+func newRedisStoreFromConfig(addr string) (RateStore, error) {
+	return nil, fmt.Errorf("ratelimit: rateLimitStore \"redis\" requires building with -tags redis")
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: MPL-2.0
+// Copyright 2025, Matteo Ragni.
+// This Source Code Form is subject to the terms of the Mozilla
+// Public License, v. 2.0. If a copy of the MPL was not distributed
+// with this file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"testing"
+)
+
+func pkcs8PEM(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal pkcs8: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestSignVerifyRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	s, err := New(pkcs8PEM(t, key))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	params := url.Values{"status": {"ok"}, "reason": {""}, "ts": {"1700000000"}}
+	sig := s.Sign(params)
+	if sig == "" {
+		t.Fatal("Sign returned empty signature")
+	}
+
+	verifyParams := cloneWith(params, "sig", sig)
+	if !s.Verify(verifyParams) {
+		t.Fatal("Verify rejected a validly signed message")
+	}
+
+	tampered := cloneWith(params, "sig", sig)
+	tampered.Set("status", "error")
+	if s.Verify(tampered) {
+		t.Fatal("Verify accepted a tampered message")
+	}
+}
+
+func TestSignVerifyEd25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	s, err := New(pkcs8PEM(t, key))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	params := url.Values{"status": {"error"}, "reason": {"rate limit"}, "ts": {"1700000001"}}
+	sig := s.Sign(params)
+	if sig == "" {
+		t.Fatal("Sign returned empty signature")
+	}
+
+	verifyParams := cloneWith(params, "sig", sig)
+	if !s.Verify(verifyParams) {
+		t.Fatal("Verify rejected a validly signed message")
+	}
+
+	tampered := cloneWith(params, "sig", sig)
+	tampered.Set("reason", "turnstile failed")
+	if s.Verify(tampered) {
+		t.Fatal("Verify accepted a tampered message")
+	}
+}
+
+func cloneWith(params url.Values, key, value string) url.Values {
+	out := url.Values{}
+	for k, v := range params {
+		out[k] = v
+	}
+	out.Set(key, value)
+	return out
+}